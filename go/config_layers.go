@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// unmarshalConfigFile unmarshals a JSON or YAML file into a map based on its extension.
+func unmarshalConfigFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]interface{}{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML file '%s': %w", path, err)
+		}
+	default:
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON file '%s': %w", path, err)
+		}
+	}
+	return out, nil
+}
+
+// RecursiveMergeNoConflict deep-merges overlay into base in place. Scalar keys
+// that exist in both maps with different values are a conflict and return an
+// error, unless the overlay key is suffixed with "!" (force-override, the
+// base value is replaced) or "+" (list-append, the overlay value is appended
+// to the base list). Nested maps are merged recursively.
+func RecursiveMergeNoConflict(base, overlay map[string]interface{}) error {
+	for rawKey, overlayVal := range overlay {
+		key := rawKey
+		force := false
+		appendList := false
+		switch {
+		case strings.HasSuffix(rawKey, "!"):
+			key = strings.TrimSuffix(rawKey, "!")
+			force = true
+		case strings.HasSuffix(rawKey, "+"):
+			key = strings.TrimSuffix(rawKey, "+")
+			appendList = true
+		}
+
+		baseVal, exists := base[key]
+		if !exists {
+			base[key] = overlayVal
+			continue
+		}
+
+		if appendList {
+			baseList, ok := baseVal.([]interface{})
+			if !ok {
+				return fmt.Errorf("cannot append to non-list key '%s'", key)
+			}
+			overlayList, ok := overlayVal.([]interface{})
+			if !ok {
+				overlayList = []interface{}{overlayVal}
+			}
+			base[key] = append(baseList, overlayList...)
+			continue
+		}
+
+		baseMap, baseIsMap := baseVal.(map[string]interface{})
+		overlayMap, overlayIsMap := overlayVal.(map[string]interface{})
+		if baseIsMap && overlayIsMap {
+			if err := RecursiveMergeNoConflict(baseMap, overlayMap); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if force || reflect.DeepEqual(baseVal, overlayVal) {
+			base[key] = overlayVal
+			continue
+		}
+
+		return fmt.Errorf("conflicting value for key '%s': use '%s!' to force-override or '%s+' to append", key, key, key)
+	}
+	return nil
+}
+
+// loadConfig loads a named configuration from the file system. It reads
+// <name>.yaml (or <name>.json) as the base and deep-merges any fragments
+// found in a sibling <name>.conf.d/*.{yaml,json} directory, in lexical order.
+func loadConfig(configName string) (*Config, error) {
+	configDirPath, err := getConfigDirPath()
+	if err != nil {
+		return nil, err
+	}
+
+	basePath := filepath.Join(configDirPath, configName+".yaml")
+	if _, err := os.Stat(basePath); os.IsNotExist(err) {
+		basePath = filepath.Join(configDirPath, configName+".json")
+	}
+
+	merged, err := unmarshalConfigFile(basePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("configuration '%s' not found", configName)
+		}
+		return nil, fmt.Errorf("failed to read config file '%s': %w", basePath, err)
+	}
+
+	confDPath := filepath.Join(configDirPath, configName+".conf.d")
+	fragments, err := filepath.Glob(filepath.Join(confDPath, "*.yaml"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob conf.d fragments: %w", err)
+	}
+	jsonFragments, err := filepath.Glob(filepath.Join(confDPath, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to glob conf.d fragments: %w", err)
+	}
+	fragments = append(fragments, jsonFragments...)
+	sort.Strings(fragments)
+
+	for _, fragment := range fragments {
+		overlay, err := unmarshalConfigFile(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read conf.d fragment '%s': %w", fragment, err)
+		}
+		if err := RecursiveMergeNoConflict(merged, overlay); err != nil {
+			return nil, fmt.Errorf("failed to merge conf.d fragment '%s': %w", fragment, err)
+		}
+	}
+
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal merged config for '%s': %w", configName, err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(mergedJSON, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merged config '%s': %w", configName, err)
+	}
+
+	return &cfg, nil
+}
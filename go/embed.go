@@ -0,0 +1,269 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/cheggaaa/pb/v3"
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed",
+	Short: "Generate embeddings for a field and write them back to the collection",
+	Long:  `Scans a MongoDB collection, generates embeddings for a source field via Voyage AI, and writes them into a target vector field in batches.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configName, _ := cmd.Flags().GetString("config")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		var cfg *Config
+		if configName != "" {
+			var err error
+			cfg, err = loadConfig(configName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return
+			}
+		}
+		if cfg == nil {
+			cfg = &Config{}
+		}
+
+		finalConfig, err := mergeConfigs(cfg, cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging configurations: %v\n", err)
+			return
+		}
+
+		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString, db, and coll must be provided either via config or flags.\n")
+			return
+		}
+
+		sourceField, _ := cmd.Flags().GetString("sourceField")
+		targetField, _ := cmd.Flags().GetString("targetField")
+		batchSize, _ := cmd.Flags().GetInt("batchSize")
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		resume, _ := cmd.Flags().GetBool("resume")
+		filterJSON, _ := cmd.Flags().GetString("filter")
+
+		// concurrency sizes the semaphore channel and batchSize gates when a
+		// batch flushes; a value below 1 for either would leave the pipeline
+		// unable to ever flush or dispatch a batch, hanging the command.
+		if batchSize < 1 {
+			batchSize = 1
+		}
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		if sourceField == "" || targetField == "" {
+			fmt.Fprintf(os.Stderr, "Error: --sourceField and --targetField are required.\n")
+			return
+		}
+
+		voyageAPIKey := finalConfig.VoyageAPIKey
+		if voyageAPIKey == "" {
+			voyageAPIKey = os.Getenv("VOYAGE_API_KEY")
+		}
+		if voyageAPIKey == "" {
+			fmt.Fprintf(os.Stderr, "Error: Voyage AI API key not provided. Set --voyageAPIKey flag, in config, or VOYAGE_API_KEY environment variable.\n")
+			return
+		}
+		voyageModel := finalConfig.VoyageModel
+
+		filter := bson.M{}
+		if filterJSON != "" {
+			if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+				fmt.Fprintf(os.Stderr, "Error parsing --filter as JSON: %v\n", err)
+				return
+			}
+		}
+		if resume {
+			filter = bson.M{"$and": []bson.M{filter, {targetField: bson.M{"$exists": false}}}}
+		}
+
+		client, err := getMongoClient(finalConfig.ConnectionString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+			return
+		}
+		defer func() {
+			if err = client.Disconnect(context.TODO()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error disconnecting from MongoDB: %v\n", err)
+			}
+		}()
+
+		collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT)
+		go func() {
+			<-sigCh
+			fmt.Println("\nReceived interrupt, finishing current batch before exiting...")
+			cancel()
+		}()
+
+		total, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error counting documents: %v\n", err)
+			return
+		}
+		if total == 0 {
+			fmt.Println("No documents to embed.")
+			return
+		}
+
+		cursor, err := collection.Find(ctx, filter, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying documents: %v\n", err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		bar := pb.StartNew(int(total))
+		defer bar.Finish()
+
+		var processed, failed int64
+		var batch []bson.M
+		flush := func(batch []bson.M) {
+			if len(batch) == 0 {
+				return
+			}
+			texts := make([]string, len(batch))
+			for i, d := range batch {
+				if s, ok := d[sourceField].(string); ok {
+					texts[i] = s
+				}
+			}
+
+			embeddings, err := getEmbeddings(texts, voyageAPIKey, voyageModel)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError getting embeddings for batch: %v\n", err)
+				atomic.AddInt64(&failed, int64(len(batch)))
+				return
+			}
+
+			models := make([]mongo.WriteModel, len(batch))
+			for i, d := range batch {
+				models[i] = mongo.NewUpdateOneModel().
+					SetFilter(bson.M{"_id": d["_id"]}).
+					SetUpdate(bson.M{"$set": bson.M{targetField: embeddings[i]}})
+			}
+
+			bwCtx, bwCancel := context.WithTimeout(context.Background(), 30*time.Second)
+			_, err = collection.BulkWrite(bwCtx, models, options.BulkWrite().SetOrdered(false))
+			bwCancel()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "\nError writing batch: %v\n", err)
+				atomic.AddInt64(&failed, int64(len(batch)))
+				return
+			}
+
+			atomic.AddInt64(&processed, int64(len(batch)))
+		}
+
+		// A ticker goroutine drives the progress bar from the atomic
+		// counters so concurrent flush() calls never touch the bar directly.
+		tickerDone := make(chan struct{})
+		var tickerWG sync.WaitGroup
+		tickerWG.Add(1)
+		go func() {
+			defer tickerWG.Done()
+			ticker := time.NewTicker(200 * time.Millisecond)
+			defer ticker.Stop()
+			render := func() {
+				bar.SetCurrent(atomic.LoadInt64(&processed) + atomic.LoadInt64(&failed))
+			}
+			for {
+				select {
+				case <-ticker.C:
+					render()
+				case <-tickerDone:
+					render()
+					return
+				}
+			}
+		}()
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, concurrency)
+		interrupted := false
+
+	loop:
+		for cursor.Next(ctx) {
+			var d bson.M
+			if err := cursor.Decode(&d); err != nil {
+				fmt.Fprintf(os.Stderr, "\nError decoding document: %v\n", err)
+				continue
+			}
+			batch = append(batch, d)
+			if len(batch) >= batchSize {
+				b := batch
+				batch = nil
+				wg.Add(1)
+				sem <- struct{}{}
+				go func() {
+					defer wg.Done()
+					defer func() { <-sem }()
+					flush(b)
+				}()
+			}
+
+			select {
+			case <-ctx.Done():
+				interrupted = true
+				break loop
+			default:
+			}
+		}
+
+		if len(batch) > 0 {
+			flush(batch)
+		}
+		wg.Wait()
+		close(tickerDone)
+		tickerWG.Wait()
+
+		if verbose {
+			fmt.Printf("\nFilter: %s\n", func() string { b, _ := json.Marshal(filter); return string(b) }())
+		}
+
+		fmt.Printf("\nProcessed %d document(s), %d failure(s) out of %d matched.\n",
+			atomic.LoadInt64(&processed), atomic.LoadInt64(&failed), total)
+		if interrupted {
+			fmt.Println("Interrupted before completion; re-run with --resume to continue where this left off.")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(embedCmd)
+
+	embedCmd.Flags().String("config", "", "The name of the configuration to use.")
+	embedCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	embedCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
+	embedCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
+	embedCmd.Flags().String("voyageAPIKey", "", "The Voyage AI API key.")
+	embedCmd.Flags().String("voyageModel", "", "The Voyage AI model to use for embedding.")
+	embedCmd.Flags().String("sourceField", "", "The field containing the text to embed.")
+	embedCmd.Flags().String("targetField", "", "The field to write the generated embedding into.")
+	embedCmd.Flags().Int("batchSize", 32, "Number of documents to embed per Voyage AI request.")
+	embedCmd.Flags().String("filter", "", "JSON filter to select which documents to embed.")
+	embedCmd.Flags().Int("concurrency", 4, "Number of batches to process concurrently.")
+	embedCmd.Flags().Bool("resume", false, "Skip documents where the target field is already set.")
+	embedCmd.Flags().Bool("verbose", false, "Enable verbose logging.")
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// redactConfig returns a copy of cfg with connectionString and voyageAPIKey
+// cleared, unless includeSecrets is set.
+func redactConfig(cfg *Config, includeSecrets bool) *Config {
+	out := *cfg
+	if !includeSecrets {
+		out.ConnectionString = ""
+		out.VoyageAPIKey = ""
+	}
+	return &out
+}
+
+// listConfigNames returns the names (without extension) of all saved configs.
+func listConfigNames(configDirPath string) ([]string, error) {
+	files, err := os.ReadDir(configDirPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, file := range files {
+		ext := filepath.Ext(file.Name())
+		if !file.IsDir() && (ext == ".json" || ext == ".yaml" || ext == ".yml") {
+			names = append(names, file.Name()[:len(file.Name())-len(ext)])
+		}
+	}
+	return names, nil
+}
+
+// dumpSearchIndexes fetches every search index definition on collection and
+// writes one JSON file per index under <dir>/indexes/<db>/<coll>/<name>.json.
+func dumpSearchIndexes(ctx context.Context, collection *mongo.Collection, db, coll, dir string) error {
+	cursor, err := collection.SearchIndexes().List(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list search indexes for %s.%s: %w", db, coll, err)
+	}
+	defer cursor.Close(ctx)
+
+	var indexes []bson.M
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	outDir := filepath.Join(dir, "indexes", db, coll)
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	for _, idx := range indexes {
+		name, _ := idx["name"].(string)
+		if name == "" {
+			continue
+		}
+		data, err := json.MarshalIndent(idx, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(outDir, name+".json"), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export <dir>",
+	Short: "Export saved configs and their Atlas Search index definitions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := args[0]
+		includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+
+		configDirPath, err := getConfigDirPath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+
+		names, err := listConfigNames(configDirPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing configurations: %v\n", err)
+			return
+		}
+		if len(names) == 0 {
+			fmt.Println("No configurations found.")
+			return
+		}
+
+		configsOutDir := filepath.Join(dir, "configs")
+		if err := os.MkdirAll(configsOutDir, 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating export directory: %v\n", err)
+			return
+		}
+
+		for _, name := range names {
+			cfg, err := loadConfig(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration '%s': %v\n", name, err)
+				continue
+			}
+
+			redacted := redactConfig(cfg, includeSecrets)
+			data, err := json.MarshalIndent(redacted, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error marshalling configuration '%s': %v\n", name, err)
+				continue
+			}
+			if err := os.WriteFile(filepath.Join(configsOutDir, name+".json"), data, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing configuration '%s': %v\n", name, err)
+				continue
+			}
+			fmt.Printf("Exported config '%s'\n", name)
+
+			if cfg.ConnectionString == "" || cfg.DB == "" || cfg.Coll == "" {
+				continue
+			}
+
+			client, err := getMongoClient(cfg.ConnectionString)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error connecting to MongoDB for config '%s': %v\n", name, err)
+				continue
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			collection := client.Database(cfg.DB).Collection(cfg.Coll)
+			if err := dumpSearchIndexes(ctx, collection, cfg.DB, cfg.Coll, dir); err != nil {
+				fmt.Fprintf(os.Stderr, "Error exporting indexes for config '%s': %v\n", name, err)
+			} else {
+				fmt.Printf("Exported search indexes for %s.%s\n", cfg.DB, cfg.Coll)
+			}
+			cancel()
+			client.Disconnect(context.TODO())
+		}
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Import saved configs and reconcile Atlas Search indexes from a previous export",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := args[0]
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		prune, _ := cmd.Flags().GetBool("prune")
+
+		if err := importConfigs(dir, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Error importing configs: %v\n", err)
+			return
+		}
+
+		// Mirror export's walk over every config, so a bare `import <dir>`
+		// reconciles indexes for all of them; --config narrows that down to
+		// a single one when only part of the export should be applied, and
+		// skips listing <dir>/configs for config names.
+		var names []string
+		if configName, _ := cmd.Flags().GetString("config"); configName != "" {
+			names = []string{configName}
+		} else {
+			var err error
+			names, err = listConfigNames(filepath.Join(dir, "configs"))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error listing exported configs: %v\n", err)
+				return
+			}
+			if len(names) == 0 {
+				fmt.Println("No configs found under <dir>/configs; skipping index reconciliation.")
+				return
+			}
+		}
+
+		for _, name := range names {
+			cfg, err := loadConfig(name)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration '%s': %v\n", name, err)
+				continue
+			}
+			finalConfig, err := mergeConfigs(cfg, cmd)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error merging configuration '%s': %v\n", name, err)
+				continue
+			}
+			if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+				fmt.Fprintf(os.Stderr, "Skipping index reconciliation for '%s': connectionString, db, and coll must be provided either via config or flags.\n", name)
+				continue
+			}
+
+			client, err := getMongoClient(finalConfig.ConnectionString)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error connecting to MongoDB for config '%s': %v\n", name, err)
+				continue
+			}
+
+			collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
+			if err := applySearchIndexes(context.Background(), collection, finalConfig.DB, finalConfig.Coll, dir, dryRun, prune); err != nil {
+				fmt.Fprintf(os.Stderr, "Error applying indexes for config '%s': %v\n", name, err)
+			}
+			client.Disconnect(context.TODO())
+		}
+	},
+}
+
+// importConfigs recreates any config found under <dir>/configs that does not
+// already exist locally. Existing configs are left untouched.
+func importConfigs(dir string, dryRun bool) error {
+	configsInDir := filepath.Join(dir, "configs")
+	files, err := filepath.Glob(filepath.Join(configsInDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	configDirPath, err := getConfigDirPath()
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := filepath.Base(file)
+		name = name[:len(name)-len(filepath.Ext(name))]
+		destPath := filepath.Join(configDirPath, name+".json")
+
+		if _, err := os.Stat(destPath); err == nil {
+			continue // already exists locally; don't overwrite
+		}
+		if _, err := os.Stat(filepath.Join(configDirPath, name+".yaml")); err == nil {
+			continue // already exists locally as YAML, which loadConfig prefers
+		}
+
+		if dryRun {
+			fmt.Printf("Would create config '%s'\n", name)
+			continue
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(configDirPath, 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(destPath, data, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Created config '%s'\n", name)
+	}
+	return nil
+}
+
+// definitionChanged reports whether live (decoded from BSON, so numbers are
+// int32/int64 and arrays are bson.A) differs from desired (decoded from JSON
+// on disk, so numbers are float64 and arrays are []interface{}). Comparing
+// the two directly with reflect.DeepEqual would always report a difference
+// because the Go types never match, so both sides are round-tripped through
+// encoding/json first to normalize them onto the same types before comparing.
+func definitionChanged(live, desired interface{}) (bool, error) {
+	liveJSON, err := json.Marshal(live)
+	if err != nil {
+		return false, err
+	}
+	var liveNormalized interface{}
+	if err := json.Unmarshal(liveJSON, &liveNormalized); err != nil {
+		return false, err
+	}
+
+	desiredJSON, err := json.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+	var desiredNormalized interface{}
+	if err := json.Unmarshal(desiredJSON, &desiredNormalized); err != nil {
+		return false, err
+	}
+
+	return !reflect.DeepEqual(liveNormalized, desiredNormalized), nil
+}
+
+// applySearchIndexes reconciles the live Atlas Search indexes on collection
+// with the definitions found under <dir>/indexes/<db>/<coll>/*.json.
+func applySearchIndexes(ctx context.Context, collection *mongo.Collection, db, coll, dir string, dryRun, prune bool) error {
+	indexDir := filepath.Join(dir, "indexes", db, coll)
+	files, err := filepath.Glob(filepath.Join(indexDir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	desired := map[string]bson.M{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var idx bson.M
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("failed to parse index file '%s': %w", file, err)
+		}
+		name, _ := idx["name"].(string)
+		if name == "" {
+			continue
+		}
+		desired[name] = idx
+	}
+
+	cursor, err := collection.SearchIndexes().List(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to list search indexes for %s.%s: %w", db, coll, err)
+	}
+	defer cursor.Close(ctx)
+
+	var live []bson.M
+	if err := cursor.All(ctx, &live); err != nil {
+		return err
+	}
+	liveByName := map[string]bson.M{}
+	for _, idx := range live {
+		if name, ok := idx["name"].(string); ok {
+			liveByName[name] = idx
+		}
+	}
+
+	for name, idx := range desired {
+		definition := idx["latestDefinition"]
+		if definition == nil {
+			definition = idx["definition"]
+		}
+		indexType, _ := idx["type"].(string)
+
+		if existing, ok := liveByName[name]; !ok {
+			if dryRun {
+				fmt.Printf("Would create search index '%s' on %s.%s\n", name, db, coll)
+				continue
+			}
+			model := mongo.SearchIndexModel{Definition: definition, Options: options.SearchIndexes().SetName(name)}
+			if indexType != "" {
+				model.Options.SetType(indexType)
+			}
+			if _, err := collection.SearchIndexes().CreateOne(ctx, model); err != nil {
+				return fmt.Errorf("failed to create search index '%s': %w", name, err)
+			}
+			fmt.Printf("Created search index '%s' on %s.%s\n", name, db, coll)
+		} else if changed, err := definitionChanged(existing["latestDefinition"], definition); err != nil {
+			return fmt.Errorf("failed to compare search index '%s': %w", name, err)
+		} else if changed {
+			if dryRun {
+				fmt.Printf("Would update search index '%s' on %s.%s\n", name, db, coll)
+				continue
+			}
+			if err := collection.SearchIndexes().UpdateOne(ctx, name, definition); err != nil {
+				return fmt.Errorf("failed to update search index '%s': %w", name, err)
+			}
+			fmt.Printf("Updated search index '%s' on %s.%s\n", name, db, coll)
+		}
+	}
+
+	if prune {
+		for name := range liveByName {
+			if _, ok := desired[name]; ok {
+				continue
+			}
+			if dryRun {
+				fmt.Printf("Would drop search index '%s' on %s.%s\n", name, db, coll)
+				continue
+			}
+			if err := collection.SearchIndexes().DropOne(ctx, name); err != nil {
+				return fmt.Errorf("failed to drop search index '%s': %w", name, err)
+			}
+			fmt.Printf("Dropped search index '%s' on %s.%s\n", name, db, coll)
+		}
+	}
+
+	return nil
+}
+
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Manage Atlas Search index definitions",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var indexDumpCmd = &cobra.Command{
+	Use:   "dump <dir>",
+	Short: "Dump the live Atlas Search index definitions for a config to disk",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := args[0]
+		configName, _ := cmd.Flags().GetString("config")
+
+		var cfg *Config
+		if configName != "" {
+			var err error
+			cfg, err = loadConfig(configName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return
+			}
+		}
+		if cfg == nil {
+			cfg = &Config{}
+		}
+		finalConfig, err := mergeConfigs(cfg, cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging configurations: %v\n", err)
+			return
+		}
+		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString, db, and coll must be provided either via config or flags.\n")
+			return
+		}
+
+		client, err := getMongoClient(finalConfig.ConnectionString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+			return
+		}
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := dumpSearchIndexes(ctx, collection, finalConfig.DB, finalConfig.Coll, dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error dumping indexes: %v\n", err)
+			return
+		}
+		fmt.Printf("Dumped search indexes for %s.%s to %s\n", finalConfig.DB, finalConfig.Coll, dir)
+	},
+}
+
+var indexApplyCmd = &cobra.Command{
+	Use:   "apply <dir>",
+	Short: "Reconcile live Atlas Search indexes with definitions on disk",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := args[0]
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		prune, _ := cmd.Flags().GetBool("prune")
+		configName, _ := cmd.Flags().GetString("config")
+
+		var cfg *Config
+		if configName != "" {
+			var err error
+			cfg, err = loadConfig(configName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return
+			}
+		}
+		if cfg == nil {
+			cfg = &Config{}
+		}
+		finalConfig, err := mergeConfigs(cfg, cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging configurations: %v\n", err)
+			return
+		}
+		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString, db, and coll must be provided either via config or flags.\n")
+			return
+		}
+
+		client, err := getMongoClient(finalConfig.ConnectionString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+			return
+		}
+		defer client.Disconnect(context.TODO())
+
+		collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
+		if err := applySearchIndexes(context.Background(), collection, finalConfig.DB, finalConfig.Coll, dir, dryRun, prune); err != nil {
+			fmt.Fprintf(os.Stderr, "Error applying indexes: %v\n", err)
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexDumpCmd)
+	indexCmd.AddCommand(indexApplyCmd)
+
+	configExportCmd.Flags().Bool("include-secrets", false, "Include connectionString and voyageAPIKey in the exported configs.")
+
+	configImportCmd.Flags().String("config", "", "Reconcile indexes for only this config name, instead of every config under <dir>/configs.")
+	configImportCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	configImportCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
+	configImportCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
+	configImportCmd.Flags().Bool("dry-run", false, "Print what would change without making changes.")
+	configImportCmd.Flags().Bool("prune", false, "Delete indexes present in Atlas but not on disk.")
+
+	indexDumpCmd.Flags().String("config", "", "The name of the configuration to use.")
+	indexDumpCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	indexDumpCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
+	indexDumpCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
+
+	indexApplyCmd.Flags().String("config", "", "The name of the configuration to use.")
+	indexApplyCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	indexApplyCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
+	indexApplyCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
+	indexApplyCmd.Flags().Bool("dry-run", false, "Print what would change without making changes.")
+	indexApplyCmd.Flags().Bool("prune", false, "Delete indexes present in Atlas but not on disk.")
+}
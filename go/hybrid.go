@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/errgroup"
+)
+
+// runRankedAggregation executes pipeline and returns the ordered list of
+// document IDs, in result order, to be used as a ranked input to RRF.
+func runRankedAggregation(ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]interface{}, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	ids := make([]interface{}, len(results))
+	for i, r := range results {
+		ids[i] = r["_id"]
+	}
+	return ids, nil
+}
+
+// fuseRRF computes Reciprocal Rank Fusion scores across the given ranked
+// result sets, each weighted independently, and returns document IDs sorted
+// by descending fused score.
+func fuseRRF(rankedSets [][]interface{}, weights []float64, k int) ([]interface{}, map[interface{}]float64, map[interface{}]map[int]int) {
+	scores := map[interface{}]float64{}
+	componentRanks := map[interface{}]map[int]int{}
+
+	for setIdx, ids := range rankedSets {
+		for i, id := range ids {
+			rank := i + 1
+			scores[id] += weights[setIdx] * (1.0 / float64(k+rank))
+			if componentRanks[id] == nil {
+				componentRanks[id] = map[int]int{}
+			}
+			componentRanks[id][setIdx] = rank
+		}
+	}
+
+	ordered := make([]interface{}, 0, len(scores))
+	for id := range scores {
+		ordered = append(ordered, id)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return scores[ordered[i]] > scores[ordered[j]]
+	})
+
+	return ordered, scores, componentRanks
+}
+
+var hybridCmd = &cobra.Command{
+	Use:   "hybrid <query>",
+	Short: "Perform a hybrid lexical + vector search fused with Reciprocal Rank Fusion",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		query := args[0]
+		configName, _ := cmd.Flags().GetString("config")
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		var cfg *Config
+		if configName != "" {
+			var err error
+			cfg, err = loadConfig(configName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return
+			}
+		}
+		if cfg == nil {
+			cfg = &Config{}
+		}
+
+		finalConfig, err := mergeConfigs(cfg, cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging configurations: %v\n", err)
+			return
+		}
+
+		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString, db, and coll must be provided either via config or flags.\n")
+			return
+		}
+		if len(finalConfig.Field) == 0 {
+			fmt.Fprintf(os.Stderr, "Error: field (the lexical search field(s)) must be provided either via config or flags.\n")
+			return
+		}
+
+		lexicalIndex, _ := cmd.Flags().GetString("lexicalIndex")
+		if lexicalIndex == "" {
+			lexicalIndex = "default"
+		}
+		vectorIndex := finalConfig.Index
+		if vectorIndex == "" {
+			vectorIndex, _ = cmd.Flags().GetString("index")
+		}
+		strategy, _ := cmd.Flags().GetString("strategy")
+		rrfK, _ := cmd.Flags().GetInt("rrfK")
+		lexicalWeight, _ := cmd.Flags().GetFloat64("lexicalWeight")
+		vectorWeight, _ := cmd.Flags().GetFloat64("vectorWeight")
+		limit, _ := cmd.Flags().GetInt("limit")
+		numCandidates, _ := cmd.Flags().GetInt("numCandidates")
+
+		// --field doubles as the lexical $search path list; the vector leg
+		// needs its own single embedding field since the two rarely match
+		// (e.g. a config built for vectorCmd sets field to the embedding
+		// field alone, which isn't mapped as text in the search index).
+		vectorField, _ := cmd.Flags().GetString("vectorField")
+		if vectorField == "" {
+			vectorField = finalConfig.Field[0]
+		}
+
+		// Mirror vectorCmd's auto-scaling of numCandidates (main.go), but off
+		// the vector leg's own candidate pool (limit*10) rather than the final
+		// --limit, so Atlas's numCandidates >= limit constraint holds once the
+		// RRF pool is widened below.
+		vectorLimit := limit * 10
+		if cmd.Flags().Changed("limit") && !cmd.Flags().Changed("numCandidates") {
+			numCandidates = vectorLimit * 10
+		}
+
+		// Hybrid fuses a lexical search over query's text with a vector
+		// search over its embedding, so query must be real text, not a raw
+		// vector: require --embedWithVoyage to derive the vector from it
+		// rather than letting a comma-separated float string leak into the
+		// lexical leg as search text.
+		embedWithVoyage, _ := cmd.Flags().GetBool("embedWithVoyage")
+		if !embedWithVoyage {
+			fmt.Fprintf(os.Stderr, "Error: hybrid requires --embedWithVoyage; <query> is used as-is for the lexical leg and must be embedded for the vector leg.\n")
+			return
+		}
+		voyageAPIKey := finalConfig.VoyageAPIKey
+		if voyageAPIKey == "" {
+			voyageAPIKey = os.Getenv("VOYAGE_API_KEY")
+		}
+		if voyageAPIKey == "" {
+			fmt.Fprintf(os.Stderr, "Error: Voyage AI API key not provided. Set --voyageAPIKey flag, in config, or VOYAGE_API_KEY environment variable.\n")
+			return
+		}
+		voyageModel, _ := cmd.Flags().GetString("voyageModel")
+		if voyageModel == "" {
+			voyageModel = finalConfig.VoyageModel
+		}
+		embeddings, err := getEmbeddings([]string{query}, voyageAPIKey, voyageModel)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting embeddings from Voyage AI: %v\n", err)
+			return
+		}
+		embedding := embeddings[0]
+
+		client, err := getMongoClient(finalConfig.ConnectionString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+			return
+		}
+		defer func() {
+			if err = client.Disconnect(context.TODO()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error disconnecting from MongoDB: %v\n", err)
+			}
+		}()
+
+		collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
+
+		searchPath := finalConfig.Field
+		lexicalStage := bson.D{{"$search", bson.D{
+			{"index", lexicalIndex},
+			{"text", bson.D{{"query", query}, {"path", searchPath}}},
+		}}}
+		vectorStage := bson.D{{"$vectorSearch", bson.D{
+			{"index", vectorIndex},
+			{"path", vectorField},
+			{"queryVector", embedding},
+			{"numCandidates", numCandidates},
+			{"limit", vectorLimit},
+		}}}
+
+		// Unlike lexicalCmd/vectorCmd, the fetch stage always keeps _id so the
+		// fused ranking can be re-applied after MongoDB returns the documents
+		// in arbitrary order; it is stripped before printing if not requested.
+		var fetchProjectStage bson.D
+		stripID := false
+		if len(finalConfig.ProjectField) > 0 {
+			stripID = true
+			projectFields := bson.D{{"_id", 1}}
+			for _, field := range finalConfig.ProjectField {
+				if field == "_id" {
+					stripID = false
+					continue
+				}
+				projectFields = append(projectFields, bson.E{Key: field, Value: 1})
+			}
+			fetchProjectStage = bson.D{{"$project", projectFields}}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		switch strategy {
+		case "server":
+			// $search and $vectorSearch may only open a top-level pipeline;
+			// Atlas rejects them inside a $unionWith sub-pipeline, so there is
+			// no single aggregation that can fuse both server-side today.
+			fmt.Fprintf(os.Stderr, "Error: --strategy=server is not supported because Atlas does not allow $search/$vectorSearch inside a $unionWith sub-pipeline. Use --strategy=client.\n")
+			return
+		case "client", "":
+			g, gctx := errgroup.WithContext(ctx)
+			var lexicalIDs, vectorIDs []interface{}
+			g.Go(func() error {
+				var err error
+				lexicalIDs, err = runRankedAggregation(gctx, collection, mongo.Pipeline{lexicalStage, {{"$limit", vectorLimit}}})
+				return err
+			})
+			g.Go(func() error {
+				var err error
+				vectorIDs, err = runRankedAggregation(gctx, collection, mongo.Pipeline{vectorStage})
+				return err
+			})
+			if err := g.Wait(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error executing aggregations: %v\n", err)
+				return
+			}
+
+			fusedIDs, scores, componentRanks := fuseRRF([][]interface{}{lexicalIDs, vectorIDs}, []float64{lexicalWeight, vectorWeight}, rrfK)
+			if len(fusedIDs) > limit {
+				fusedIDs = fusedIDs[:limit]
+			}
+
+			fetchPipeline := mongo.Pipeline{{{"$match", bson.D{{"_id", bson.D{{"$in", fusedIDs}}}}}}}
+			if fetchProjectStage != nil {
+				fetchPipeline = append(fetchPipeline, fetchProjectStage)
+			}
+			cursor, err := collection.Aggregate(ctx, fetchPipeline)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching fused results: %v\n", err)
+				return
+			}
+			defer cursor.Close(ctx)
+
+			var docs []bson.M
+			if err := cursor.All(ctx, &docs); err != nil {
+				fmt.Fprintf(os.Stderr, "Error reading results: %v\n", err)
+				return
+			}
+
+			// $match does not guarantee order, so re-key by id and re-apply
+			// the fused ranking afterwards.
+			keyed := map[interface{}]bson.M{}
+			for _, doc := range docs {
+				if id, ok := doc["_id"]; ok {
+					keyed[id] = doc
+				}
+			}
+
+			ordered := make([]bson.M, 0, len(fusedIDs))
+			for _, id := range fusedIDs {
+				doc, ok := keyed[id]
+				if !ok {
+					continue
+				}
+				if stripID {
+					delete(doc, "_id")
+				}
+				if verbose {
+					doc["_rrfScore"] = scores[id]
+					doc["_componentRanks"] = componentRanks[id]
+				}
+				ordered = append(ordered, doc)
+			}
+			printResults(ordered)
+		default:
+			fmt.Fprintf(os.Stderr, "Error: unknown --strategy '%s', expected 'client' or 'server'\n", strategy)
+		}
+	},
+}
+
+func printResults(results []bson.M) {
+	if len(results) == 0 {
+		fmt.Println("No results found.")
+		return
+	}
+	resultsJSON, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshalling results: %v\n", err)
+		return
+	}
+	fmt.Println(string(resultsJSON))
+}
+
+func init() {
+	rootCmd.AddCommand(hybridCmd)
+
+	hybridCmd.Flags().String("config", "", "The name of the configuration to use.")
+	hybridCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	hybridCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
+	hybridCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
+	hybridCmd.Flags().StringArray("field", []string{}, "The lexical search field(s). Can be specified multiple times. Field[0] also seeds --vectorField if that is unset.")
+	hybridCmd.Flags().String("vectorField", "", "The vector search path for the vector leg. Defaults to the first --field value if unset.")
+	hybridCmd.Flags().StringArray("projectField", []string{}, "The field to project. Can be specified multiple times.")
+	hybridCmd.Flags().String("index", "vector_index", "The name of the search index to use for vector search.")
+	hybridCmd.Flags().String("lexicalIndex", "default", "The name of the search index to use for lexical search.")
+	hybridCmd.Flags().Int("numCandidates", 100, "Number of candidates to consider for approximate vector search.")
+	hybridCmd.Flags().Int("limit", 10, "Number of fused results to return.")
+	hybridCmd.Flags().Int("rrfK", 60, "The k constant used in the Reciprocal Rank Fusion formula.")
+	hybridCmd.Flags().Float64("lexicalWeight", 1.0, "Weight applied to the lexical result set's RRF contribution.")
+	hybridCmd.Flags().Float64("vectorWeight", 1.0, "Weight applied to the vector result set's RRF contribution.")
+	hybridCmd.Flags().String("strategy", "client", "Fusion strategy: only 'client' (fuse in Go) is currently supported; 'server' is rejected with an explanatory error.")
+	hybridCmd.Flags().Bool("embedWithVoyage", false, "Embed the query with Voyage AI. Required: hybrid needs query to be both real search text and an embeddable vector.")
+	hybridCmd.Flags().String("voyageModel", "voyage-3.5", "The Voyage AI model to use for embedding.")
+	hybridCmd.Flags().String("voyageAPIKey", "", "The Voyage AI API key.")
+	hybridCmd.Flags().Bool("verbose", false, "Enable verbose logging.")
+}
@@ -39,32 +39,6 @@ func getConfigDirPath() (string, error) {
 	return configDirPath, nil
 }
 
-// loadConfig loads a named configuration from the file system.
-func loadConfig(configName string) (*Config, error) {
-	configDirPath, err := getConfigDirPath()
-	if err != nil {
-		return nil, err
-	}
-	configFilePath := filepath.Join(configDirPath, configName+".json")
-
-	data, err := os.ReadFile(configFilePath)
-	
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("configuration '%s' not found", configName)
-		}
-		return nil, fmt.Errorf("failed to read config file '%s': %w", configFilePath, err)
-	}
-
-	var cfg Config
-	err = json.Unmarshal(data, &cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config file '%s': %w", configFilePath, err)
-	}
-
-	return &cfg, nil
-}
-
 // mergeConfigs merges a base configuration with command-line flags.
 // Command-line flags take precedence.
 func mergeConfigs(baseConfig *Config, cmd *cobra.Command) (*Config, error) {
@@ -124,19 +98,24 @@ func getMongoClient(connectionString string) (*mongo.Client, error) {
 	return client, nil
 }
 
-// getEmbeddings fetches embeddings from Voyage AI.
-func getEmbeddings(query, apiKey, model string) ([]float64, error) {
+// getEmbeddings fetches embeddings for one or more inputs from Voyage AI in a
+// single batched request, returning one embedding per input in the same order.
+func getEmbeddings(inputs []string, apiKey, model string) ([][]float64, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("Voyage AI API key is not provided")
 	}
 
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no inputs provided for embedding")
+	}
+
 	if model == "" {
 		model = "voyage-3.5" // Default model as per README
 	}
 
 	url := "https://api.voyageai.com/v1/embeddings"
 	payload := map[string]interface{}{
-		"input": []string{query},
+		"input": inputs,
 		"model": model,
 	}
 	jsonPayload, err := json.Marshal(payload)
@@ -151,7 +130,7 @@ func getEmbeddings(query, apiKey, model string) ([]float64, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+apiKey)
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 30 * time.Second}
 	res, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send HTTP request to Voyage AI: %w", err)
@@ -165,6 +144,7 @@ func getEmbeddings(query, apiKey, model string) ([]float64, error) {
 
 	var result struct {
 		Data []struct {
+			Index     int       `json:"index"`
 			Embedding []float64 `json:"embedding"`
 		} `json:"data"`
 	}
@@ -179,11 +159,24 @@ func getEmbeddings(query, apiKey, model string) ([]float64, error) {
 		return nil, fmt.Errorf("failed to unmarshal Voyage AI response: %w", err)
 	}
 
-	if len(result.Data) == 0 || len(result.Data[0].Embedding) == 0 {
+	if len(result.Data) == 0 {
 		return nil, fmt.Errorf("no embeddings found in Voyage AI response")
 	}
 
-	return result.Data[0].Embedding, nil
+	embeddings := make([][]float64, len(inputs))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(embeddings) {
+			return nil, fmt.Errorf("Voyage AI response contained out-of-range index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+	for i, e := range embeddings {
+		if len(e) == 0 {
+			return nil, fmt.Errorf("no embedding returned for input at index %d", i)
+		}
+	}
+
+	return embeddings, nil
 }
 
 // parseVectorString parses a comma-separated string of floats into a []float64.
@@ -200,6 +193,43 @@ func parseVectorString(s string) ([]float64, error) {
 	return vector, nil
 }
 
+// resolveJSONOperator reads a raw JSON operator body from either the --json
+// flag or the --json-file flag (the file taking precedence if both are set)
+// and unmarshals it as MongoDB extended JSON into a bson.D. It returns a nil
+// body and no error when neither flag was supplied.
+func resolveJSONOperator(cmd *cobra.Command) (bson.D, error) {
+	jsonStr, _ := cmd.Flags().GetString("json")
+	jsonFile, _ := cmd.Flags().GetString("json-file")
+
+	if jsonFile != "" {
+		data, err := os.ReadFile(jsonFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --json-file '%s': %w", jsonFile, err)
+		}
+		jsonStr = string(data)
+	}
+
+	if jsonStr == "" {
+		return nil, nil
+	}
+
+	var body bson.D
+	if err := bson.UnmarshalExtJSON([]byte(jsonStr), false, &body); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON operator payload: %w", err)
+	}
+	return body, nil
+}
+
+// hasKey reports whether a bson.D already contains the given top-level key.
+func hasKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "atlas-search",
 	Short: "A command-line interface for querying MongoDB Atlas Search.",
@@ -288,8 +318,9 @@ var configListCmd = &cobra.Command{
 		fmt.Println("Available Configurations:")
 		found := false
 		for _, file := range files {
-			if !file.IsDir() && filepath.Ext(file.Name()) == ".json" {
-				fmt.Printf("- %s\n", file.Name()[:len(file.Name())-len(filepath.Ext(file.Name()))])
+			ext := filepath.Ext(file.Name())
+			if !file.IsDir() && (ext == ".json" || ext == ".yaml" || ext == ".yml") {
+				fmt.Printf("- %s\n", file.Name()[:len(file.Name())-len(ext)])
 				found = true
 			}
 		}
@@ -348,15 +379,30 @@ var lexicalCmd = &cobra.Command{
 
 		collection := client.Database(finalConfig.DB).Collection(finalConfig.Coll)
 
+		jsonOperator, err := resolveJSONOperator(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+
 		// Build the $search stage
-		searchPath := finalConfig.Field
-		if len(searchPath) == 0 {
-			searchPath = []string{"*"} // Default to wildcard if no field is specified
+		var searchBody bson.D
+		if jsonOperator != nil {
+			searchBody = jsonOperator
+			if !hasKey(searchBody, "index") {
+				searchBody = append(searchBody, bson.E{Key: "index", Value: finalConfig.Index})
+			}
+		} else {
+			searchPath := finalConfig.Field
+			if len(searchPath) == 0 {
+				searchPath = []string{"*"} // Default to wildcard if no field is specified
+			}
+			searchBody = bson.D{
+				{"index", finalConfig.Index},
+				{"text", bson.D{{"query", query}, {"path", searchPath}}},
+			}
 		}
-		searchStage := bson.D{{"$search", bson.D{
-			{"index", finalConfig.Index},
-			{"text", bson.D{{"query", query}, {"path", searchPath}}}},
-		}}
+		searchStage := bson.D{{"$search", searchBody}}
 
 		// Build the $project stage
 		projectStage := bson.D{{"$project", bson.D{}}}
@@ -437,14 +483,30 @@ var vectorCmd = &cobra.Command{
 			return
 		}
 
-		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" || finalConfig.Field == nil || len(finalConfig.Field) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: connectionString, db, coll, and field must be provided either via config or flags.\n")
+		jsonOperator, err := resolveJSONOperator(cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+
+		if finalConfig.ConnectionString == "" || finalConfig.DB == "" || finalConfig.Coll == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString, db, and coll must be provided either via config or flags.\n")
+			return
+		}
+		if len(finalConfig.Field) == 0 && (jsonOperator == nil || !hasKey(jsonOperator, "path")) {
+			fmt.Fprintf(os.Stderr, "Error: field must be provided either via config or flags (or set path in the --json payload).\n")
 			return
 		}
 
 		var embedding []float64
 		embedWithVoyage, _ := cmd.Flags().GetBool("embedWithVoyage")
-		if embedWithVoyage {
+		// --json may already carry a precomputed queryVector (e.g. a raw
+		// `near` payload); in that case <query> is unused and must not be
+		// forced through embedding/parsing.
+		jsonHasQueryVector := jsonOperator != nil && hasKey(jsonOperator, "queryVector")
+		if jsonHasQueryVector {
+			// embedding stays nil; vectorBody below takes queryVector from jsonOperator.
+		} else if embedWithVoyage {
 			voyageAPIKey := finalConfig.VoyageAPIKey
 			if voyageAPIKey == "" {
 				voyageAPIKey = os.Getenv("VOYAGE_API_KEY") // Fallback to environment variable
@@ -460,11 +522,12 @@ var vectorCmd = &cobra.Command{
 			}
 
 			fmt.Println("Fetching embeddings from Voyage AI...")
-			embedding, err = getEmbeddings(query, voyageAPIKey, voyageModel)
+			embeddings, err := getEmbeddings([]string{query}, voyageAPIKey, voyageModel)
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Error getting embeddings from Voyage AI: %v\n", err)
 				return
 			}
+			embedding = embeddings[0]
 			if verbose {
 				fmt.Printf("Embedding: %v\n", embedding)
 			}
@@ -503,13 +566,31 @@ var vectorCmd = &cobra.Command{
 		}
 
 		// Build the $vectorSearch stage
-		vectorSearchStage := bson.D{{"$vectorSearch", bson.D{
-			{"index", finalConfig.Index},
-			{"path", finalConfig.Field[0]}, // Assuming single field for vector search as per README example
-			{"queryVector", embedding},
-			{"numCandidates", numCandidates},
-			{"limit", limit},
-		}}}
+		var vectorBody bson.D
+		if jsonOperator != nil {
+			vectorBody = jsonOperator
+			if !hasKey(vectorBody, "index") {
+				vectorBody = append(vectorBody, bson.E{Key: "index", Value: finalConfig.Index})
+			}
+			if !hasKey(vectorBody, "path") && len(finalConfig.Field) > 0 {
+				vectorBody = append(vectorBody, bson.E{Key: "path", Value: finalConfig.Field[0]})
+			}
+			if !hasKey(vectorBody, "queryVector") {
+				vectorBody = append(vectorBody, bson.E{Key: "queryVector", Value: embedding})
+			}
+			if !hasKey(vectorBody, "limit") {
+				vectorBody = append(vectorBody, bson.E{Key: "limit", Value: limit})
+			}
+		} else {
+			vectorBody = bson.D{
+				{"index", finalConfig.Index},
+				{"path", finalConfig.Field[0]}, // Assuming single field for vector search as per README example
+				{"queryVector", embedding},
+				{"numCandidates", numCandidates},
+				{"limit", limit},
+			}
+		}
+		vectorSearchStage := bson.D{{"$vectorSearch", vectorBody}}
 
 		// Build the $project stage
 		projectStage := bson.D{{"$project", bson.D{}}}
@@ -586,11 +667,12 @@ func init() {
 	lexicalCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
 	lexicalCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
 	lexicalCmd.Flags().Bool("verbose", false, "Enable verbose logging.")
+	lexicalCmd.Flags().String("json", "", "Raw JSON body for the $search operator. Overrides --field.")
+	lexicalCmd.Flags().String("json-file", "", "Path to a file containing the raw JSON body for the $search operator. Overrides --json.")
 
 	// Add flags for vectorCmd
 	vectorCmd.Flags().String("config", "", "The name of the configuration to use.")
-	vectorCmd.Flags().String("field", "", "The field to search for vectors. This is a required argument.")
-	vectorCmd.MarkFlagRequired("field") // Mark field as required for vectorCmd
+	vectorCmd.Flags().String("field", "", "The field to search for vectors. Required unless the --json payload sets path.")
 	vectorCmd.Flags().StringArray("projectField", []string{}, "The field to project. Can be specified multiple times.")
 	vectorCmd.Flags().String("index", "vector_index", "The name of the search index to use. Defaults to vector_index.")
 	vectorCmd.Flags().Int("numCandidates", 100, "Number of candidates to consider for approximate vector search.")
@@ -602,6 +684,8 @@ func init() {
 	vectorCmd.Flags().String("db", "", "Database name. Overrides the configured value.")
 	vectorCmd.Flags().String("coll", "", "Collection name. Overrides the configured value.")
 	vectorCmd.Flags().Bool("verbose", false, "Enable verbose logging.")
+	vectorCmd.Flags().String("json", "", "Raw JSON body for the $vectorSearch operator. Overrides --field.")
+	vectorCmd.Flags().String("json-file", "", "Path to a file containing the raw JSON body for the $vectorSearch operator. Overrides --json.")
 }
 
 func Execute() {
@@ -0,0 +1,598 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// The wire messages below mirror proto/atlassearch.proto. They are plain Go
+// structs encoded with the jsonCodec rather than generated protobuf code, so
+// the service can be hand-wired without running protoc in this tree.
+
+type LexicalRequest struct {
+	DB           string   `json:"db"`
+	Coll         string   `json:"coll"`
+	Index        string   `json:"index"`
+	Field        []string `json:"field"`
+	Query        string   `json:"query"`
+	ProjectField []string `json:"projectField"`
+}
+
+type VectorRequest struct {
+	DB            string    `json:"db"`
+	Coll          string    `json:"coll"`
+	Index         string    `json:"index"`
+	Field         string    `json:"field"`
+	Query         string    `json:"query"`
+	QueryVector   []float64 `json:"queryVector"`
+	NumCandidates int       `json:"numCandidates"`
+	Limit         int       `json:"limit"`
+	ProjectField  []string  `json:"projectField"`
+}
+
+type HybridRequest struct {
+	Lexical       LexicalRequest `json:"lexical"`
+	Vector        VectorRequest  `json:"vector"`
+	RRFK          int            `json:"rrfK"`
+	LexicalWeight float64        `json:"lexicalWeight"`
+	VectorWeight  float64        `json:"vectorWeight"`
+}
+
+type SearchResponse struct {
+	DocumentsJSON []string `json:"documentsJson"`
+}
+
+type FollowMatchesRequest struct {
+	DB          string    `json:"db"`
+	Coll        string    `json:"coll"`
+	Mode        string    `json:"mode"`  // "lexical" or "vector"
+	Field       string    `json:"field"` // document field holding the vector, for "vector" mode
+	Query       string    `json:"query"`
+	QueryVector []float64 `json:"queryVector"`
+	Threshold   float64   `json:"threshold"`
+}
+
+type FollowMatchesResponse struct {
+	Heartbeat    bool    `json:"heartbeat"`
+	DocumentJSON string  `json:"documentJson"`
+	Score        float64 `json:"score"`
+}
+
+// jsonCodec is a grpc codec that marshals messages as JSON instead of
+// protobuf wire format, so the service can run without generated stubs.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return "json" }
+
+// searchServer implements the AtlasSearchService handlers against a live
+// MongoDB client and the CLI's existing search helpers.
+type searchServer struct {
+	client    *mongo.Client
+	followers *followerRegistry
+
+	// voyageAPIKey/voyageModel are the server's own configured Voyage AI
+	// credentials, used to embed FollowMatches queries that arrive as text
+	// rather than a pre-computed vector. They are never accepted from a
+	// request so a client cannot exfiltrate the server's API key.
+	voyageAPIKey string
+	voyageModel  string
+}
+
+// follower is a single active FollowMatches stream.
+type follower struct {
+	id   string
+	send func(*FollowMatchesResponse) error
+	done chan struct{}
+}
+
+// followerRegistry tracks active FollowMatches streams so shutdown can signal
+// each of them to stop. Each stream reads its own change stream; events are
+// not fanned out through the registry.
+type followerRegistry struct {
+	mu        sync.Mutex
+	followers map[string]*follower
+}
+
+func newFollowerRegistry() *followerRegistry {
+	return &followerRegistry{followers: map[string]*follower{}}
+}
+
+func (r *followerRegistry) add(f *follower) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.followers[f.id] = f
+}
+
+func (r *followerRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.followers, id)
+}
+
+func (r *followerRegistry) snapshot() []*follower {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*follower, 0, len(r.followers))
+	for _, f := range r.followers {
+		out = append(out, f)
+	}
+	return out
+}
+
+func (s *searchServer) Lexical(ctx context.Context, req *LexicalRequest) (*SearchResponse, error) {
+	searchPath := req.Field
+	if len(searchPath) == 0 {
+		searchPath = []string{"*"}
+	}
+	pipeline := mongo.Pipeline{
+		{{"$search", bson.D{{"index", req.Index}, {"text", bson.D{{"query", req.Query}, {"path", searchPath}}}}}},
+	}
+	return s.runAggregation(ctx, req.DB, req.Coll, pipeline, req.ProjectField)
+}
+
+func (s *searchServer) Vector(ctx context.Context, req *VectorRequest) (*SearchResponse, error) {
+	pipeline := mongo.Pipeline{
+		{{"$vectorSearch", bson.D{
+			{"index", req.Index},
+			{"path", req.Field},
+			{"queryVector", req.QueryVector},
+			{"numCandidates", req.NumCandidates},
+			{"limit", req.Limit},
+		}}},
+	}
+	return s.runAggregation(ctx, req.DB, req.Coll, pipeline, req.ProjectField)
+}
+
+func (s *searchServer) Hybrid(ctx context.Context, req *HybridRequest) (*SearchResponse, error) {
+	lexicalPipeline := mongo.Pipeline{
+		{{"$search", bson.D{{"index", req.Lexical.Index}, {"text", bson.D{{"query", req.Lexical.Query}, {"path", req.Lexical.Field}}}}}},
+	}
+	vectorPipeline := mongo.Pipeline{
+		{{"$vectorSearch", bson.D{
+			{"index", req.Vector.Index},
+			{"path", req.Vector.Field},
+			{"queryVector", req.Vector.QueryVector},
+			{"numCandidates", req.Vector.NumCandidates},
+			{"limit", req.Vector.Limit},
+		}}},
+	}
+
+	collection := s.client.Database(req.Lexical.DB).Collection(req.Lexical.Coll)
+	lexicalIDs, err := runRankedAggregation(ctx, collection, lexicalPipeline)
+	if err != nil {
+		return nil, fmt.Errorf("lexical leg failed: %w", err)
+	}
+	vectorIDs, err := runRankedAggregation(ctx, collection, vectorPipeline)
+	if err != nil {
+		return nil, fmt.Errorf("vector leg failed: %w", err)
+	}
+
+	fusedIDs, _, _ := fuseRRF([][]interface{}{lexicalIDs, vectorIDs}, []float64{req.LexicalWeight, req.VectorWeight}, req.RRFK)
+	if req.Vector.Limit > 0 && len(fusedIDs) > req.Vector.Limit {
+		fusedIDs = fusedIDs[:req.Vector.Limit]
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{{{"$match", bson.D{{"_id", bson.D{{"$in", fusedIDs}}}}}}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return toSearchResponse(results)
+}
+
+func (s *searchServer) runAggregation(ctx context.Context, db, coll string, pipeline mongo.Pipeline, projectField []string) (*SearchResponse, error) {
+	if len(projectField) > 0 {
+		projectFields := bson.D{}
+		for _, field := range projectField {
+			projectFields = append(projectFields, bson.E{Key: field, Value: 1})
+		}
+		projectFields = append(projectFields, bson.E{Key: "_id", Value: 0})
+		pipeline = append(pipeline, bson.D{{"$project", projectFields}})
+	}
+
+	collection := s.client.Database(db).Collection(coll)
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []bson.M
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	return toSearchResponse(results)
+}
+
+func toSearchResponse(results []bson.M) (*SearchResponse, error) {
+	docs := make([]string, len(results))
+	for i, r := range results {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = string(b)
+	}
+	return &SearchResponse{DocumentsJSON: docs}, nil
+}
+
+// FollowMatches opens a change stream on req.DB/req.Coll and, for every
+// insert/update, re-scores the document against the caller's query. Matches
+// at or above req.Threshold are streamed back; a heartbeat is sent every 10
+// seconds so idle clients can detect a dead connection.
+func (s *searchServer) FollowMatches(req *FollowMatchesRequest, send func(*FollowMatchesResponse) error, done <-chan struct{}) error {
+	collection := s.client.Database(req.DB).Collection(req.Coll)
+
+	queryVector := req.QueryVector
+	if req.Mode == "vector" && len(queryVector) == 0 {
+		if s.voyageAPIKey == "" {
+			return fmt.Errorf("vector mode requires a queryVector or a Voyage AI API key configured on the server")
+		}
+		embeddings, err := getEmbeddings([]string{req.Query}, s.voyageAPIKey, s.voyageModel)
+		if err != nil {
+			return fmt.Errorf("failed to embed follow query: %w", err)
+		}
+		queryVector = embeddings[0]
+	}
+	if req.Mode == "vector" && req.Field == "" {
+		return fmt.Errorf("vector mode requires field to be set to the document's vector field")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := collection.Watch(ctx, mongo.Pipeline{
+		{{"$match", bson.D{{"operationType", bson.D{{"$in", bson.A{"insert", "update", "replace"}}}}}}},
+	}, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(ctx)
+
+	id := fmt.Sprintf("%p", &req)
+	f := &follower{id: id, send: send, done: make(chan struct{})}
+	s.followers.add(f)
+	defer s.followers.remove(id)
+
+	heartbeat := time.NewTicker(10 * time.Second)
+	defer heartbeat.Stop()
+
+	queryTerms := strings.Fields(strings.ToLower(req.Query))
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-f.done:
+			return nil
+		case <-heartbeat.C:
+			if err := send(&FollowMatchesResponse{Heartbeat: true}); err != nil {
+				return err
+			}
+		default:
+		}
+
+		if !stream.TryNext(ctx) {
+			if err := stream.Err(); err != nil {
+				return fmt.Errorf("change stream error: %w", err)
+			}
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+
+		var event struct {
+			FullDocument bson.M `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			continue
+		}
+		if event.FullDocument == nil {
+			continue
+		}
+
+		var score float64
+		switch req.Mode {
+		case "vector":
+			score = cosineSimilarity(queryVector, floatSliceFromDoc(event.FullDocument, req.Field))
+		default:
+			score = lexicalMatchScore(queryTerms, event.FullDocument)
+		}
+
+		if score < req.Threshold {
+			continue
+		}
+
+		docJSON, err := json.Marshal(event.FullDocument)
+		if err != nil {
+			continue
+		}
+		if err := send(&FollowMatchesResponse{DocumentJSON: string(docJSON), Score: score}); err != nil {
+			return err
+		}
+	}
+}
+
+// lexicalMatchScore is a naive term-overlap score: the fraction of query
+// terms that appear anywhere in the document's string fields.
+func lexicalMatchScore(queryTerms []string, doc bson.M) float64 {
+	if len(queryTerms) == 0 {
+		return 0
+	}
+	text := strings.ToLower(flattenStrings(doc))
+	matched := 0
+	for _, term := range queryTerms {
+		if strings.Contains(text, term) {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(queryTerms))
+}
+
+func flattenStrings(doc bson.M) string {
+	var b strings.Builder
+	for _, v := range doc {
+		if s, ok := v.(string); ok {
+			b.WriteString(s)
+			b.WriteString(" ")
+		}
+	}
+	return b.String()
+}
+
+// floatSliceFromDoc reads field from doc and converts it to a []float64. The
+// field is expected to hold a BSON array of numbers, as produced by the
+// embed command; any other shape returns nil.
+func floatSliceFromDoc(doc bson.M, field string) []float64 {
+	raw, ok := doc[field]
+	if !ok {
+		return nil
+	}
+
+	arr, ok := raw.(bson.A)
+	if !ok {
+		return nil
+	}
+
+	vector := make([]float64, len(arr))
+	for i, v := range arr {
+		switch n := v.(type) {
+		case float64:
+			vector[i] = n
+		case float32:
+			vector[i] = float64(n)
+		case int32:
+			vector[i] = float64(n)
+		case int64:
+			vector[i] = float64(n)
+		case int:
+			vector[i] = float64(n)
+		default:
+			return nil
+		}
+	}
+	return vector
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) == 0 || len(b) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the CLI as a long-lived gRPC service for lexical, vector, hybrid, and follow-match queries",
+	Long:  `Boots a gRPC service exposing Lexical, Vector, and Hybrid unary RPCs plus a streaming FollowMatches RPC for tailing new matches against a MongoDB change stream.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configName, _ := cmd.Flags().GetString("config")
+		var cfg *Config
+		if configName != "" {
+			var err error
+			cfg, err = loadConfig(configName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+				return
+			}
+		}
+		if cfg == nil {
+			cfg = &Config{}
+		}
+		finalConfig, err := mergeConfigs(cfg, cmd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error merging configurations: %v\n", err)
+			return
+		}
+		if finalConfig.ConnectionString == "" {
+			fmt.Fprintf(os.Stderr, "Error: connectionString must be provided either via config or flags.\n")
+			return
+		}
+
+		client, err := getMongoClient(finalConfig.ConnectionString)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to MongoDB: %v\n", err)
+			return
+		}
+		defer client.Disconnect(context.TODO())
+
+		network, _ := cmd.Flags().GetString("network")
+		address, _ := cmd.Flags().GetString("address")
+		certFile, _ := cmd.Flags().GetString("tlsCert")
+		keyFile, _ := cmd.Flags().GetString("tlsKey")
+		clientCAFile, _ := cmd.Flags().GetString("tlsClientCA")
+
+		lis, err := net.Listen(network, address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listening on %s %s: %v\n", network, address, err)
+			return
+		}
+
+		var opts []grpc.ServerOption
+		opts = append(opts, grpc.ForceServerCodec(jsonCodec{}))
+		if certFile != "" && keyFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error loading TLS certificate: %v\n", err)
+				return
+			}
+			tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+			if clientCAFile != "" {
+				caData, err := os.ReadFile(clientCAFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error reading client CA file: %v\n", err)
+					return
+				}
+				pool := x509.NewCertPool()
+				pool.AppendCertsFromPEM(caData)
+				tlsConfig.ClientCAs = pool
+				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+
+		voyageAPIKey := finalConfig.VoyageAPIKey
+		if voyageAPIKey == "" {
+			voyageAPIKey = os.Getenv("VOYAGE_API_KEY")
+		}
+
+		grpcServer := grpc.NewServer(opts...)
+		server := &searchServer{
+			client:       client,
+			followers:    newFollowerRegistry(),
+			voyageAPIKey: voyageAPIKey,
+			voyageModel:  finalConfig.VoyageModel,
+		}
+		grpcServer.RegisterService(&atlasSearchServiceDesc, server)
+
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+		go func() {
+			<-sigCh
+			fmt.Println("\nShutting down, draining active streams...")
+
+			// FollowMatches only returns when its own done channel is closed
+			// or the stream's context is cancelled; GracefulStop never cancels
+			// those, so unblock every follower first or it would hang forever.
+			for _, f := range server.followers.snapshot() {
+				close(f.done)
+			}
+
+			stopped := make(chan struct{})
+			go func() {
+				grpcServer.GracefulStop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(10 * time.Second):
+				fmt.Println("Streams did not drain in time, forcing shutdown.")
+				grpcServer.Stop()
+			}
+		}()
+
+		fmt.Printf("Listening on %s %s\n", network, address)
+		if err := grpcServer.Serve(lis); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving: %v\n", err)
+		}
+	},
+}
+
+// atlasSearchServiceDesc wires the searchServer methods into grpc's generic
+// dispatch machinery without requiring protoc-generated stubs.
+var atlasSearchServiceDesc = grpc.ServiceDesc{
+	ServiceName: "atlassearch.AtlasSearchService",
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Lexical",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(LexicalRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(*searchServer).Lexical(ctx, req)
+			},
+		},
+		{
+			MethodName: "Vector",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(VectorRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(*searchServer).Vector(ctx, req)
+			},
+		},
+		{
+			MethodName: "Hybrid",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+				req := new(HybridRequest)
+				if err := dec(req); err != nil {
+					return nil, err
+				}
+				return srv.(*searchServer).Hybrid(ctx, req)
+			},
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "FollowMatches",
+			ServerStreams: true,
+			Handler: func(srv interface{}, stream grpc.ServerStream) error {
+				req := new(FollowMatchesRequest)
+				if err := stream.RecvMsg(req); err != nil {
+					return err
+				}
+				return srv.(*searchServer).FollowMatches(req, func(resp *FollowMatchesResponse) error {
+					return stream.SendMsg(resp)
+				}, stream.Context().Done())
+			},
+		},
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("config", "", "The name of the configuration to use.")
+	serveCmd.Flags().String("connectionString", "", "MongoDB connection string. Overrides the configured value.")
+	serveCmd.Flags().String("network", "tcp", "Listener network: 'tcp' or 'unix'.")
+	serveCmd.Flags().String("address", ":50051", "Address to bind: host:port for tcp, or a socket path for unix.")
+	serveCmd.Flags().String("tlsCert", "", "Path to a TLS certificate for mTLS.")
+	serveCmd.Flags().String("tlsKey", "", "Path to the TLS private key for mTLS.")
+	serveCmd.Flags().String("tlsClientCA", "", "Path to a CA bundle for verifying client certificates (enables mTLS).")
+}